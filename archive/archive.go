@@ -0,0 +1,415 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 15:00:00
+ * @LastEditTime: 2026-07-27 15:00:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/archive/archive.go
+ */
+
+// Package archive layers restic-style named snapshots on top of
+// ltouwrap's sequential file model, turning a LtoNoRewindTapeDrive into
+// a usable backup target instead of a thin mt/sg_logs wrapper.
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/FunctionSir/ltouwrap"
+)
+
+var ErrSnapshotNotFound error = errors.New("archive: no such snapshot")
+var ErrCanNotReadCatalog error = errors.New("archive: can not read tape catalog")
+var ErrCanNotWriteCatalog error = errors.New("archive: can not write tape catalog")
+var ErrCanNotWriteSnapshot error = errors.New("archive: can not write snapshot")
+var ErrCanNotReadSnapshot error = errors.New("archive: can not read snapshot index")
+var ErrCanNotRestoreSnapshot error = errors.New("archive: can not restore snapshot")
+var ErrUnsafeTarEntry error = errors.New("archive: tar entry escapes destination directory")
+
+// filesPerSnapshot is how many tape files each WriteSnapshot call
+// consumes: the tar data file, its JSON index, and a fresh full copy of
+// the tape-level catalog appended right after it. A sequential-access
+// drive can't overwrite a file in the middle of the tape without
+// destroying everything physically recorded after it, so the catalog is
+// never rewritten in place: every snapshot appends its own copy, and
+// readCatalog walks forward to find the newest one.
+const filesPerSnapshot uint64 = 3
+
+// EntryMeta describes one file stored inside a snapshot's tar stream.
+// Path is the name the file was stored under in the tar stream: the
+// walked root's base name joined with its path relative to that root,
+// never an absolute host path.
+type EntryMeta struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+	SHA256 string `json:"sha256"`
+}
+
+// SnapshotMeta is the on-tape index written right after a snapshot's
+// tar data file, and what ListSnapshots returns.
+type SnapshotMeta struct {
+	Name       string      `json:"name"`
+	Timestamp  time.Time   `json:"timestamp"`
+	MediumSN   string      `json:"medium_sn"`
+	FileNumber uint64      `json:"file_number"` // Tape file number of the data file.
+	ByteSize   int64       `json:"byte_size"`
+	Entries    []EntryMeta `json:"entries"`
+}
+
+// catalog is the tape-level index of every snapshot on the tape.
+type catalog struct {
+	Snapshots []SnapshotMeta `json:"snapshots"`
+}
+
+// Archive wraps a LtoNoRewindTapeDrive with named-snapshot semantics.
+type Archive struct {
+	Drive *ltouwrap.LtoNoRewindTapeDrive
+}
+
+// New wraps drive with snapshot/index semantics.
+func New(drive *ltouwrap.LtoNoRewindTapeDrive) *Archive {
+	return &Archive{Drive: drive}
+}
+
+// WriteOptions tweaks how WriteSnapshot records a snapshot.
+type WriteOptions struct {
+	// Now, if set, is used instead of time.Now for the snapshot
+	// timestamp. Tests can set this.
+	Now func() time.Time
+}
+
+func (o WriteOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// WriteSnapshot tars roots onto the tape as one file (terminated with a
+// filemark), writes a second file holding the snapshot's JSON index
+// right after it, WEOFs again, then appends a fresh full copy of the
+// tape-level catalog as a third file so ListSnapshots can find it
+// without ever rewriting anything earlier on the tape.
+func (a *Archive) WriteSnapshot(ctx context.Context, name string, roots []string, opts WriteOptions) (SnapshotMeta, error) {
+	cat, err := a.readCatalog(ctx)
+	if err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	dataFileNumber := filesPerSnapshot * uint64(len(cat.Snapshots))
+	if err := a.seekToFile(ctx, dataFileNumber); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	entries, byteSize, err := a.writeTarFile(ctx, roots)
+	if err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	if err := a.Drive.WEOFCtx(ctx); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	sn, err := a.Drive.GetMediumSN()
+	if err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	meta := SnapshotMeta{
+		Name:       name,
+		Timestamp:  opts.now(),
+		MediumSN:   sn,
+		FileNumber: dataFileNumber,
+		ByteSize:   byteSize,
+		Entries:    entries,
+	}
+	if err := a.writeIndexFile(ctx, meta); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	if err := a.Drive.WEOFCtx(ctx); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	cat.Snapshots = append(cat.Snapshots, meta)
+	if err := a.appendCatalog(ctx, cat); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	return meta, nil
+}
+
+// ListSnapshots rewinds and walks forward to the newest tape-level
+// catalog copy, skipping each snapshot's tar data and index files by
+// filemark rather than reading them.
+func (a *Archive) ListSnapshots(ctx context.Context) ([]SnapshotMeta, error) {
+	cat, err := a.readCatalog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCanNotReadCatalog, err)
+	}
+	return cat.Snapshots, nil
+}
+
+// RestoreSnapshot seeks directly to name's data file using the file
+// number recorded in the catalog, and untars it into dest.
+func (a *Archive) RestoreSnapshot(ctx context.Context, name string, dest string) (SnapshotMeta, error) {
+	cat, err := a.readCatalog(ctx)
+	if err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotRestoreSnapshot, err)
+	}
+	var meta *SnapshotMeta
+	for i := range cat.Snapshots {
+		if cat.Snapshots[i].Name == name {
+			meta = &cat.Snapshots[i]
+			break
+		}
+	}
+	if meta == nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotRestoreSnapshot, ErrSnapshotNotFound)
+	}
+	if err := a.seekToFile(ctx, meta.FileNumber); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotRestoreSnapshot, err)
+	}
+	if err := a.untarCurrentFile(ctx, dest); err != nil {
+		return SnapshotMeta{}, fmt.Errorf("%w: %w", ErrCanNotRestoreSnapshot, err)
+	}
+	return *meta, nil
+}
+
+// seekToFile rewinds and spaces forward to the start of tape file n.
+func (a *Archive) seekToFile(ctx context.Context, n uint64) error {
+	if err := a.Drive.RewindCtx(ctx); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	return a.Drive.FSFCtx(ctx, uint32(n))
+}
+
+// readCatalog rewinds, then repeatedly spaces forward by two filemarks
+// (over one snapshot's data and index files) and reads the file that
+// follows. Every such file is a full copy of the catalog as of the
+// snapshot before it, so the last one successfully read is the newest.
+// Hitting blank tape or the physical end of recorded data ends the walk
+// without error: on a fresh tape that means there is no catalog yet.
+func (a *Archive) readCatalog(ctx context.Context) (catalog, error) {
+	if err := a.Drive.RewindCtx(ctx); err != nil {
+		return catalog{}, err
+	}
+	var cat catalog
+	for {
+		if err := a.Drive.FSFCtx(ctx, 2); err != nil {
+			break
+		}
+		raw, err := a.readCurrentFile(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return catalog{}, err
+		}
+		if len(raw) == 0 {
+			break
+		}
+		var next catalog
+		if err := json.Unmarshal(raw, &next); err != nil {
+			return catalog{}, fmt.Errorf("%w: %w", ErrCanNotReadCatalog, err)
+		}
+		cat = next
+	}
+	return cat, nil
+}
+
+// appendCatalog writes cat as the tape file right after the snapshot
+// that produced it, at the drive's current position, and WEOFs. It
+// never rewinds or rewrites anything earlier on the tape: a
+// sequential-access drive can't overwrite a file mid-tape without
+// destroying everything recorded after it, so every snapshot gets its
+// own fresh catalog copy instead.
+func (a *Archive) appendCatalog(ctx context.Context, cat catalog) error {
+	raw, err := json.Marshal(cat)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCanNotWriteCatalog, err)
+	}
+	if err := a.writeCurrentFile(ctx, raw); err != nil {
+		return fmt.Errorf("%w: %w", ErrCanNotWriteCatalog, err)
+	}
+	return a.Drive.WEOFCtx(ctx)
+}
+
+func (a *Archive) writeIndexFile(ctx context.Context, meta SnapshotMeta) error {
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCanNotWriteSnapshot, err)
+	}
+	return a.writeCurrentFile(ctx, raw)
+}
+
+// writeCurrentFile writes raw to the device file at its current tape
+// position through a TapeWriter, so small index/catalog writes are
+// shaped the same way the bulk tar data is.
+func (a *Archive) writeCurrentFile(ctx context.Context, raw []byte) error {
+	w, err := a.Drive.TapeWriter(ctx, ltouwrap.TapeIOOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// readCurrentFile reads the tape file at the current position through a
+// TapeReader, until it hits the trailing filemark.
+func (a *Archive) readCurrentFile(ctx context.Context) ([]byte, error) {
+	r, err := a.Drive.TapeReader(ctx, ltouwrap.TapeIOOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// writeTarFile streams roots as a tar archive through a TapeWriter, so
+// a slow source (e.g. a network filesystem) doesn't shoe-shine the
+// drive, and returns per-entry metadata plus the total bytes written.
+func (a *Archive) writeTarFile(ctx context.Context, roots []string) ([]EntryMeta, int64, error) {
+	w, err := a.Drive.TapeWriter(ctx, ltouwrap.TapeIOOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	tw := tar.NewWriter(w)
+	var entries []EntryMeta
+	var offset int64
+	for _, root := range roots {
+		base := filepath.Base(filepath.Clean(root))
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimPrefix(filepath.ToSlash(filepath.Join(base, rel)), "/")
+			hdr.Name = name
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			h := sha256.New()
+			if _, err := io.Copy(io.MultiWriter(tw, h), in); err != nil {
+				return err
+			}
+			entries = append(entries, EntryMeta{
+				Path:   name,
+				Size:   info.Size(),
+				Offset: offset,
+				SHA256: hex.EncodeToString(h.Sum(nil)),
+			})
+			// archive/tar pads each body with zeros to a whole block.
+			offset += headerSize(hdr) + (info.Size()+511)/512*512
+			return nil
+		})
+		if err != nil {
+			w.Close()
+			return nil, 0, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		w.Close()
+		return nil, 0, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, err
+	}
+	// tw.Close ended the stream with two zero blocks.
+	return entries, offset + 2*512, nil
+}
+
+// untarCurrentFile reads the tar stream at the tape's current position
+// and extracts it under dest, following the extraction pattern common
+// to tar-based archivers: mkdir for directories, then copy file bodies.
+func (a *Archive) untarCurrentFile(ctx context.Context, dest string) error {
+	r, err := a.Drive.TapeReader(ctx, ltouwrap.TapeIOOptions{})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		safeName, err := sanitizeTarEntryName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, safeName)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sanitizeTarEntryName rejects an absolute path or one that climbs out
+// of the extraction root via "..", so a corrupted or tampered tar entry
+// can't make untarCurrentFile write outside dest.
+func sanitizeTarEntryName(name string) (string, error) {
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeTarEntry, name)
+	}
+	return clean, nil
+}
+
+// headerSize returns how many bytes hdr occupies in the tar stream
+// before its body, rounded up to the tar block size like archive/tar
+// does internally.
+func headerSize(hdr *tar.Header) int64 {
+	const blockSize = 512
+	blocks := int64(1)
+	if len(hdr.Name) > 100 {
+		blocks++
+	}
+	return blocks * blockSize
+}
@@ -0,0 +1,223 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 17:30:00
+ * @LastEditTime: 2026-07-27 17:30:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/archive/archive_test.go
+ */
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/FunctionSir/ltouwrap"
+)
+
+// fakeTapeExecutor simulates a no-rewind tape drive's file-number
+// bookkeeping against a set of plain temp files standing in for
+// individual tape files, so TapeWriter/TapeReader's raw os.File reads
+// and writes land on the right file as Archive rewinds/FSFs around it.
+//
+// Linux's st driver auto-advances one filemark on every read that runs
+// into one, on top of whatever FSF count was requested; since reads go
+// straight through the device file and never touch this Executor, that
+// advance is folded in here instead: the first FSF after a rewind is
+// taken at face value (nothing has been read yet to imply the bonus),
+// and every later FSF in the same rewind epoch gets +1.
+type fakeTapeExecutor struct {
+	device         *ltouwrap.LtoNoRewindTapeDrive
+	files          []string
+	pos            int
+	highestWritten int
+	fsfSinceRewind int
+	mediumSN       string
+}
+
+func newFakeTapeDrive(t *testing.T, mediumSN string) *ltouwrap.LtoNoRewindTapeDrive {
+	t.Helper()
+	dir := t.TempDir()
+	files := make([]string, 32)
+	for i := range files {
+		p := filepath.Join(dir, fmt.Sprintf("file%02d", i))
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatalf("seed tape file %d: %v", i, err)
+		}
+		files[i] = p
+	}
+	device := &ltouwrap.LtoNoRewindTapeDrive{DeviceFile: files[0]}
+	device.Executor = &fakeTapeExecutor{
+		device:         device,
+		files:          files,
+		highestWritten: -1,
+		mediumSN:       mediumSN,
+	}
+	return device
+}
+
+func (e *fakeTapeExecutor) RunMt(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("fake tape: short mt invocation %v", args)
+	}
+	switch cmd := args[2]; cmd {
+	case "rewind":
+		e.pos = 0
+		e.fsfSinceRewind = 0
+	case "fsf":
+		n, err := strconv.Atoi(args[3])
+		if err != nil {
+			return nil, fmt.Errorf("fake tape: bad fsf count %q: %w", args[3], err)
+		}
+		bonus := 0
+		if e.fsfSinceRewind > 0 {
+			bonus = 1 // the read since the previous fsf consumed its own filemark
+		}
+		target := e.pos + n + bonus
+		if target > e.highestWritten+1 || target >= len(e.files) {
+			return nil, errors.New("fake tape: fsf past recorded data")
+		}
+		e.pos = target
+		e.fsfSinceRewind++
+	case "weof":
+		if e.pos > e.highestWritten {
+			e.highestWritten = e.pos
+		}
+		e.pos++
+		if e.pos >= len(e.files) {
+			return nil, errors.New("fake tape: out of simulated tape")
+		}
+	default:
+		return nil, fmt.Errorf("fake tape: unsupported mt command %q", cmd)
+	}
+	e.device.DeviceFile = e.files[e.pos]
+	return []byte("ok\n"), nil
+}
+
+func (e *fakeTapeExecutor) RunSgReadAttr(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("fake tape: short sg_read_attr invocation %v", args)
+	}
+	switch id := args[1]; id {
+	case "0x0408":
+		return []byte(fmt.Sprintf("Attribute %sh: 0x00", id[2:])), nil
+	case "0x0401":
+		return []byte(fmt.Sprintf("Attribute %sh: %s", id[2:], e.mediumSN)), nil
+	default:
+		return nil, fmt.Errorf("fake tape: no canned sg_read_attr for %q", id)
+	}
+}
+
+func (e *fakeTapeExecutor) RunSgLogs(ctx context.Context, args ...string) ([]byte, error) {
+	return nil, errors.New("fake tape: sg_logs not simulated")
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+		t.Fatalf("mkdir for %s: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestWriteListRestoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	drive := newFakeTapeDrive(t, "LTOSN0001")
+	a := New(drive)
+
+	root1 := t.TempDir()
+	writeTestFile(t, root1, "hello.txt", "hello from snapshot one")
+
+	meta1, err := a.WriteSnapshot(ctx, "snap1", []string{root1}, WriteOptions{})
+	if err != nil {
+		t.Fatalf("WriteSnapshot snap1: %v", err)
+	}
+	if meta1.FileNumber != 0 {
+		t.Fatalf("snap1 FileNumber = %d, want 0", meta1.FileNumber)
+	}
+
+	snaps, err := a.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatalf("ListSnapshots after snap1: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Name != "snap1" {
+		t.Fatalf("ListSnapshots after snap1 = %+v, want one snapshot named snap1", snaps)
+	}
+
+	root2 := t.TempDir()
+	writeTestFile(t, root2, "world.txt", "hello from snapshot two")
+
+	meta2, err := a.WriteSnapshot(ctx, "snap2", []string{root2}, WriteOptions{})
+	if err != nil {
+		t.Fatalf("WriteSnapshot snap2: %v", err)
+	}
+	if meta2.FileNumber != filesPerSnapshot {
+		t.Fatalf("snap2 FileNumber = %d, want %d", meta2.FileNumber, filesPerSnapshot)
+	}
+
+	snaps, err = a.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatalf("ListSnapshots after snap2: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].Name != "snap1" || snaps[1].Name != "snap2" {
+		t.Fatalf("ListSnapshots after snap2 = %+v, want [snap1 snap2]", snaps)
+	}
+
+	dest := t.TempDir()
+	restored, err := a.RestoreSnapshot(ctx, "snap1", dest)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot snap1: %v", err)
+	}
+	if restored.Name != "snap1" {
+		t.Fatalf("RestoreSnapshot returned %+v, want snap1", restored)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, filepath.Base(root1), "hello.txt"))
+	if err != nil {
+		t.Fatalf("read restored file: %v", err)
+	}
+	if string(got) != "hello from snapshot one" {
+		t.Fatalf("restored content = %q, want %q", got, "hello from snapshot one")
+	}
+
+	if _, err := a.RestoreSnapshot(ctx, "no-such-snapshot", dest); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("RestoreSnapshot unknown name err = %v, want ErrSnapshotNotFound", err)
+	}
+}
+
+func TestSanitizeTarEntryName(t *testing.T) {
+	cases := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"root/a.txt", "root/a.txt", false},
+		{"./root/a.txt", "root/a.txt", false},
+		{"/etc/passwd", "", true},
+		{"../../etc/passwd", "", true},
+		{"..", "", true},
+	}
+	for _, c := range cases {
+		got, err := sanitizeTarEntryName(c.name)
+		if c.wantErr {
+			if !errors.Is(err, ErrUnsafeTarEntry) {
+				t.Errorf("sanitizeTarEntryName(%q) err = %v, want ErrUnsafeTarEntry", c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sanitizeTarEntryName(%q): %v", c.name, err)
+			continue
+		}
+		if filepath.ToSlash(got) != c.want {
+			t.Errorf("sanitizeTarEntryName(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 14:30:00
+ * @LastEditTime: 2026-07-27 14:30:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/executor.go
+ */
+
+package ltouwrap
+
+import "context"
+
+// Executor runs mt, sg_logs and sg_read_attr on behalf of a
+// LtoNoRewindTapeDrive. Implementing this lets callers mock tape
+// hardware in tests; see the faketest subpackage for a canned one.
+type Executor interface {
+	RunMt(ctx context.Context, args ...string) ([]byte, error)
+	RunSgLogs(ctx context.Context, args ...string) ([]byte, error)
+	RunSgReadAttr(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// execExecutor is the default Executor, running the real binaries via
+// os/exec. It is what every LtoNoRewindTapeDrive used before Executor
+// existed, and what device.executor() falls back to when Executor is nil.
+type execExecutor struct {
+	mt         string
+	sgLogs     string
+	sgReadAttr string
+}
+
+func (e *execExecutor) RunMt(ctx context.Context, args ...string) ([]byte, error) {
+	return getCmdOutputCtx(ctx, e.mt, args...)
+}
+
+func (e *execExecutor) RunSgLogs(ctx context.Context, args ...string) ([]byte, error) {
+	return getCmdOutputCtx(ctx, e.sgLogs, args...)
+}
+
+func (e *execExecutor) RunSgReadAttr(ctx context.Context, args ...string) ([]byte, error) {
+	return getCmdOutputCtx(ctx, e.sgReadAttr, args...)
+}
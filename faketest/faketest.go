@@ -0,0 +1,88 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 14:30:00
+ * @LastEditTime: 2026-07-27 14:30:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/faketest/faketest.go
+ */
+
+// Package faketest provides a fake ltouwrap.Executor so that code built
+// on LtoNoRewindTapeDrive can be unit-tested without real tape hardware
+// or sg3_utils/mt installed.
+package faketest
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrNoCannedOutput is returned when FakeExecutor has no canned response
+// for the command it was asked to run.
+var ErrNoCannedOutput error = errors.New("faketest: no canned output for this command")
+
+// FakeExecutor implements ltouwrap.Executor by looking up canned output
+// keyed by the space-joined args of the call. Set fields directly, or
+// use On* to register responses.
+type FakeExecutor struct {
+	Mt         map[string]Response
+	SgLogs     map[string]Response
+	SgReadAttr map[string]Response
+}
+
+// Response is the canned result of one command invocation.
+type Response struct {
+	Output []byte
+	Err    error
+}
+
+// NewFakeExecutor returns a FakeExecutor ready to have responses
+// registered on it via OnMt/OnSgLogs/OnSgReadAttr.
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{
+		Mt:         map[string]Response{},
+		SgLogs:     map[string]Response{},
+		SgReadAttr: map[string]Response{},
+	}
+}
+
+// OnMt registers the output mt should produce when called with args.
+func (f *FakeExecutor) OnMt(output string, args ...string) {
+	f.Mt[key(args)] = Response{Output: []byte(output)}
+}
+
+// OnSgLogs registers the output sg_logs should produce when called with args.
+func (f *FakeExecutor) OnSgLogs(output string, args ...string) {
+	f.SgLogs[key(args)] = Response{Output: []byte(output)}
+}
+
+// OnSgReadAttr registers the output sg_read_attr should produce when
+// called with args.
+func (f *FakeExecutor) OnSgReadAttr(output string, args ...string) {
+	f.SgReadAttr[key(args)] = Response{Output: []byte(output)}
+}
+
+func (f *FakeExecutor) RunMt(ctx context.Context, args ...string) ([]byte, error) {
+	return lookup(f.Mt, args)
+}
+
+func (f *FakeExecutor) RunSgLogs(ctx context.Context, args ...string) ([]byte, error) {
+	return lookup(f.SgLogs, args)
+}
+
+func (f *FakeExecutor) RunSgReadAttr(ctx context.Context, args ...string) ([]byte, error) {
+	return lookup(f.SgReadAttr, args)
+}
+
+func lookup(responses map[string]Response, args []string) ([]byte, error) {
+	resp, ok := responses[key(args)]
+	if !ok {
+		return nil, ErrNoCannedOutput
+	}
+	return resp.Output, resp.Err
+}
+
+func key(args []string) string {
+	return strings.Join(args, " ")
+}
@@ -29,6 +29,13 @@ type LtoNoRewindTapeDrive struct {
 	SgLogs     string
 	SgReadAttr string
 	Mt         string
+	// Backend picks how this drive is driven. Zero value is
+	// BackendUtils, so existing callers keep the historical behaviour.
+	Backend BackendKind
+	// Executor runs mt/sg_logs/sg_read_attr. A nil Executor falls back
+	// to the os/exec based implementation, so existing callers don't
+	// need to set it.
+	Executor Executor
 }
 
 type LtoTapeCapacityLog struct {
@@ -38,6 +45,26 @@ type LtoTapeCapacityLog struct {
 	AlternatePartitionMax       int64 // In MiB (1 MiB = 1048576 Bytes).
 }
 
+// One of the 64 standard TapeAlert conditions, from LOG SENSE page 2Eh.
+type TapeAlertFlag struct {
+	ID       uint8
+	Name     string
+	Severity string // One of "Information", "Warning", "Critical".
+	Active   bool
+}
+
+// Aggregated drive health, combining the Read/Write Error Counter log
+// pages (02h/03h), the Device Statistics log page (14h), and TapeAlert.
+type DriveStats struct {
+	ReadErrors        int64
+	WriteErrors       int64
+	LifetimeMBRead    int64
+	LifetimeMBWritten int64
+	LoadCount         int64
+	ThreadCount       int64
+	TapeAlerts        []TapeAlertFlag
+}
+
 // A magic number as "Capacity Unknown".
 const UnknownCapacity int64 = -1
 
@@ -143,6 +170,9 @@ func (device *LtoNoRewindTapeDrive) ChkDevice() error {
 
 // Related attribute ID is 0408h.
 func (device *LtoNoRewindTapeDrive) HasDataCartridge() (bool, error) {
+	if device.useSgIo() {
+		return viaSgIo(device, (*SgIoBackend).HasDataCartridge)
+	}
 	attrStr, err := device.ExecSgReadAttr("0x0408")
 	if err != nil {
 		return false, err
@@ -154,9 +184,56 @@ func (device *LtoNoRewindTapeDrive) HasDataCartridge() (bool, error) {
 	return val == int64(ReadAttrMediumTypeDataCartridge), nil
 }
 
+// useSgIo reports whether device should be driven via the SG_IO ioctl
+// rather than by shelling out to mt/sg_logs/sg_read_attr.
+func (device *LtoNoRewindTapeDrive) useSgIo() bool {
+	switch device.Backend {
+	case BackendSgIo:
+		return true
+	case BackendAuto:
+		return SupportsSgIo(device.DeviceFile)
+	default:
+		return false
+	}
+}
+
+func (device *LtoNoRewindTapeDrive) sgIoBackend() (*SgIoBackend, error) {
+	return NewSgIoBackend(device.DeviceFile)
+}
+
+// viaSgIo runs fn against a freshly opened SgIoBackend and closes it
+// afterwards. Every sg_io-capable method in this file dispatches through
+// this instead of repeating the open/defer-Close/call boilerplate itself.
+func viaSgIo[T any](device *LtoNoRewindTapeDrive, fn func(*SgIoBackend) (T, error)) (T, error) {
+	b, err := device.sgIoBackend()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer b.Close()
+	return fn(b)
+}
+
+// sgIoNoResult adapts an SgIoBackend method that only returns error into
+// the (T, error) shape viaSgIo expects.
+func sgIoNoResult(fn func(*SgIoBackend) error) func(*SgIoBackend) (struct{}, error) {
+	return func(b *SgIoBackend) (struct{}, error) {
+		return struct{}{}, fn(b)
+	}
+}
+
+// executor returns device.Executor, defaulting to the os/exec based
+// implementation driven by device.Mt/SgLogs/SgReadAttr.
+func (device *LtoNoRewindTapeDrive) executor() Executor {
+	if device.Executor != nil {
+		return device.Executor
+	}
+	return &execExecutor{mt: device.Mt, sgLogs: device.SgLogs, sgReadAttr: device.SgReadAttr}
+}
+
 // Use IDs like 0x0408 or 0408h.
 func (device *LtoNoRewindTapeDrive) ExecSgReadAttr(id string) (string, error) {
-	cmdOut, err := getCmdOutput(device.SgReadAttr, "-f", id, device.DeviceFile)
+	cmdOut, err := device.executor().RunSgReadAttr(context.Background(), "-f", id, device.DeviceFile)
 	if err != nil {
 		return "", fmt.Errorf("%w: %w", ErrSgReadAttrExecFailed, err)
 	}
@@ -170,9 +247,12 @@ func (device *LtoNoRewindTapeDrive) TryReadAttr() error {
 
 // From the Tape Capacity log page (code is 31h).
 func (device *LtoNoRewindTapeDrive) GetCapacityLog() (LtoTapeCapacityLog, error) {
+	if device.useSgIo() {
+		return viaSgIo(device, (*SgIoBackend).GetCapacityLog)
+	}
 	var errs error
 	capLog := LtoTapeCapacityLog{-1, -1, -1, -1}
-	cmdOut, err := getCmdOutput(device.SgLogs, "-p", "0x31", device.DeviceFile)
+	cmdOut, err := device.executor().RunSgLogs(context.Background(), "-p", "0x31", device.DeviceFile)
 	if err != nil {
 		errs = errors.Join(errs, fmt.Errorf("%w: %w", ErrSgLogsExecFailed, err))
 		return capLog, errs
@@ -202,6 +282,13 @@ func (device *LtoNoRewindTapeDrive) GetCapacityLog() (LtoTapeCapacityLog, error)
 
 // From attribute ID 0401h.
 func (device *LtoNoRewindTapeDrive) GetMediumSN() (string, error) {
+	if device.useSgIo() {
+		sn, err := viaSgIo(device, (*SgIoBackend).GetMediumSN)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrCanNotGetMediumSN, err)
+		}
+		return sn, nil
+	}
 	if err := device.TryReadAttr(); err != nil {
 		return "", fmt.Errorf("%w: %w", ErrCanNotGetMediumSN, err)
 	}
@@ -213,11 +300,19 @@ func (device *LtoNoRewindTapeDrive) GetMediumSN() (string, error) {
 }
 
 func (device *LtoNoRewindTapeDrive) Rewind(timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Rewind))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "rewind")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) RewindCtx(ctx context.Context) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Rewind))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "rewind")
 	return err
 }
@@ -284,21 +379,37 @@ func (device *LtoNoRewindTapeDrive) PrevFileCtx(ctx context.Context) error {
 }
 
 func (device *LtoNoRewindTapeDrive) FSF(count uint32, timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult(func(b *SgIoBackend) error { return b.FSF(count) }))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "fsf", count)
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) FSFCtx(ctx context.Context, count uint32) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult(func(b *SgIoBackend) error { return b.FSF(count) }))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "fsf", count)
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) BSF(count uint32, timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult(func(b *SgIoBackend) error { return b.BSF(count) }))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "bsf", count)
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) BSFCtx(ctx context.Context, count uint32) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult(func(b *SgIoBackend) error { return b.BSF(count) }))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "bsf", count)
 	return err
 }
@@ -314,31 +425,55 @@ func (device *LtoNoRewindTapeDrive) BSFMCtx(ctx context.Context, count uint32) e
 }
 
 func (device *LtoNoRewindTapeDrive) Erase(timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Erase))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "erase")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) EraseCtx(ctx context.Context) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Erase))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "erase")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) Eject(timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Eject))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "eject")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) EjectCtx(ctx context.Context) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).Eject))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "eject")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) WEOF(timeout time.Duration) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).WEOF))
+		return err
+	}
 	_, err := device.ExecMtCmd(timeout, "weof")
 	return err
 }
 
 func (device *LtoNoRewindTapeDrive) WEOFCtx(ctx context.Context) error {
+	if device.useSgIo() {
+		_, err := viaSgIo(device, sgIoNoResult((*SgIoBackend).WEOF))
+		return err
+	}
 	_, err := device.ExecMtCmdCtx(ctx, "weof")
 	return err
 }
@@ -388,7 +523,7 @@ func (device *LtoNoRewindTapeDrive) ExecMtCmdCtx(ctx context.Context, cmd string
 	for _, x := range args {
 		argsStrs = append(argsStrs, strconv.FormatUint(uint64(x), 10))
 	}
-	return getCmdOutputCtx(ctx, device.Mt, argsStrs...)
+	return device.executor().RunMt(ctx, argsStrs...)
 }
 
 func parseSgLogsCapacityLine(line string, target *int64, baseErr error) error {
@@ -0,0 +1,152 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 16:00:00
+ * @LastEditTime: 2026-07-27 16:00:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/ltouwrap_test.go
+ */
+
+package ltouwrap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FunctionSir/ltouwrap/faketest"
+)
+
+func newTestDevice(exec Executor) LtoNoRewindTapeDrive {
+	return LtoNoRewindTapeDrive{DeviceFile: "/dev/nst0", Executor: exec}
+}
+
+func TestGetCapacityLog(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgLogs(
+		"Main partition remaining capacity: 1000 MB\n"+
+			"Alternate partition remaining capacity: 0 MB\n"+
+			"Main partition maximum capacity: 2500 MB\n"+
+			"Alternate partition maximum capacity: 0 MB\n",
+		"-p", "0x31", "/dev/nst0")
+	device := newTestDevice(fe)
+	got, err := device.GetCapacityLog()
+	if err != nil {
+		t.Fatalf("GetCapacityLog: %v", err)
+	}
+	want := LtoTapeCapacityLog{
+		MainPartitionRemaining:      1000,
+		AlternatePartitionRemaining: 0,
+		MainPartitionMax:            2500,
+		AlternatePartitionMax:       0,
+	}
+	if got != want {
+		t.Fatalf("GetCapacityLog = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetCapacityLogMissingField(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgLogs("Main partition remaining capacity: 1000 MB\n", "-p", "0x31", "/dev/nst0")
+	device := newTestDevice(fe)
+	_, err := device.GetCapacityLog()
+	if !errors.Is(err, ErrSomeCapacityLogFieldsMissing) {
+		t.Fatalf("GetCapacityLog err = %v, want ErrSomeCapacityLogFieldsMissing", err)
+	}
+}
+
+func TestHasDataCartridge(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgReadAttr("Attribute 0408h: 0x00", "-f", "0x0408", "/dev/nst0")
+	device := newTestDevice(fe)
+	has, err := device.HasDataCartridge()
+	if err != nil {
+		t.Fatalf("HasDataCartridge: %v", err)
+	}
+	if !has {
+		t.Fatalf("HasDataCartridge = false, want true")
+	}
+}
+
+func TestGetCurFileNumber(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgReadAttr("Attribute 0408h: 0x00", "-f", "0x0408", "/dev/nst0")
+	fe.OnMt("SCSI 2 tape drive:\nFile number=3, block number=0, partition=0.\n", "-f", "/dev/nst0", "status")
+	device := newTestDevice(fe)
+	n, err := device.GetCurFileNumber()
+	if err != nil {
+		t.Fatalf("GetCurFileNumber: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("GetCurFileNumber = %d, want 3", n)
+	}
+}
+
+func TestCountFilesStopsOnFirstFSFError(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgReadAttr("Attribute 0408h: 0x00", "-f", "0x0408", "/dev/nst0")
+	fe.OnMt("rewound\n", "-f", "/dev/nst0", "rewind")
+	device := newTestDevice(fe)
+	cnt, err := device.CountFiles(time.Second)
+	if cnt != 0 {
+		t.Fatalf("CountFiles cnt = %d, want 0", cnt)
+	}
+	if !errors.Is(err, faketest.ErrNoCannedOutput) {
+		t.Fatalf("CountFiles err = %v, want ErrNoCannedOutput", err)
+	}
+}
+
+func TestExtractSgIntVal(t *testing.T) {
+	v, err := extractSgIntVal("Main partition remaining capacity: 1,234 MB", 0)
+	if err != nil {
+		t.Fatalf("extractSgIntVal: %v", err)
+	}
+	if v != 1234 {
+		t.Fatalf("extractSgIntVal = %d, want 1234", v)
+	}
+	if _, err := extractSgIntVal("Main partition remaining capacity:", 0); err == nil {
+		t.Fatalf("extractSgIntVal: want error on empty value")
+	}
+}
+
+func TestExtractSgStrVal(t *testing.T) {
+	v, err := extractSgStrVal("Attribute 0401h: ABC123")
+	if err != nil {
+		t.Fatalf("extractSgStrVal: %v", err)
+	}
+	if v != "ABC123" {
+		t.Fatalf("extractSgStrVal = %q, want %q", v, "ABC123")
+	}
+	if _, err := extractSgStrVal("no colon here"); err == nil {
+		t.Fatalf("extractSgStrVal: want error when there is no colon")
+	}
+}
+
+func TestExtractMtUintVal(t *testing.T) {
+	v, err := extractMtUintVal("File number=3", 0)
+	if err != nil {
+		t.Fatalf("extractMtUintVal: %v", err)
+	}
+	if v != 3 {
+		t.Fatalf("extractMtUintVal = %d, want 3", v)
+	}
+	if _, err := extractMtUintVal("File number=", 0); err == nil {
+		t.Fatalf("extractMtUintVal: want error on empty value")
+	}
+}
+
+func TestParseSgLogsCapacityLine(t *testing.T) {
+	var target int64
+	if err := parseSgLogsCapacityLine("Main partition maximum capacity: 2500 MB", &target, ErrCanNotParseMainPartitionMax); err != nil {
+		t.Fatalf("parseSgLogsCapacityLine: %v", err)
+	}
+	if target != 2500 {
+		t.Fatalf("parseSgLogsCapacityLine target = %d, want 2500", target)
+	}
+	if err := parseSgLogsCapacityLine("Main partition maximum capacity:", &target, ErrCanNotParseMainPartitionMax); !errors.Is(err, ErrCanNotParseMainPartitionMax) {
+		t.Fatalf("parseSgLogsCapacityLine err = %v, want ErrCanNotParseMainPartitionMax", err)
+	}
+	if target != UnknownCapacity {
+		t.Fatalf("parseSgLogsCapacityLine target = %d, want UnknownCapacity on error", target)
+	}
+}
@@ -0,0 +1,406 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 14:00:00
+ * @LastEditTime: 2026-07-27 14:00:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/sgio.go
+ */
+
+package ltouwrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// BackendKind selects how LtoNoRewindTapeDrive talks to the drive.
+type BackendKind int
+
+const (
+	// BackendUtils shells out to mt, sg_logs and sg_read_attr. This is
+	// the historical, and still default, behaviour of this package.
+	BackendUtils BackendKind = iota
+	// BackendSgIo talks to the drive directly through the SG_IO ioctl,
+	// so the operations SgIoBackend covers don't need sg3_utils or mt
+	// installed. GetCurFileNumber, BSFM/BSFMCtx, PrevFile/PrevFileCtx and
+	// ChkDevice still run mt with this backend.
+	BackendSgIo
+	// BackendAuto picks BackendSgIo if the device file supports SG_IO,
+	// falling back to BackendUtils otherwise.
+	BackendAuto
+)
+
+var ErrSgIoUnavailable error = errors.New("device file does not support sg_io")
+var ErrSgIoOpenFailed error = errors.New("can not open device file for sg_io")
+var ErrSgIoExecFailed error = errors.New("sg_io ioctl failed")
+var ErrSgIoCheckCondition error = errors.New("sg_io command ended with check condition")
+var ErrSgIoBadResponse error = errors.New("can not parse sg_io response")
+
+// Linux sg_io_hdr_t, see <scsi/sg.h>. Field order and sizes mirror the
+// kernel struct exactly, so this must not be reordered.
+type sgIoHdr struct {
+	InterfaceID    int32
+	DxferDirection int32
+	CmdLen         uint8
+	MxSbLen        uint8
+	IovecCount     uint16
+	DxferLen       uint32
+	Dxferp         uintptr
+	Cmdp           uintptr
+	Sbp            uintptr
+	Timeout        uint32
+	Flags          uint32
+	PackID         int32
+	UsrPtr         uintptr
+	Status         uint8
+	MaskedStatus   uint8
+	MsgStatus      uint8
+	SbLenWr        uint8
+	HostStatus     uint16
+	DriverStatus   uint16
+	Resid          int32
+	Duration       uint32
+	Info           uint32
+}
+
+const (
+	sgDxferNone     int32         = -1
+	sgDxferToDev    int32         = -2
+	sgDxferFromDev  int32         = -3
+	sgInterfaceIDS  int32         = 'S'
+	sgIoTimeoutMs   time.Duration = 60000
+	sgIoIoctlNumber               = 0x2285 // SG_IO, see <scsi/sg.h>.
+)
+
+// SgIoBackend issues raw SCSI CDBs to a tape drive's device file via the
+// SG_IO ioctl, avoiding a dependency on sg_logs, sg_read_attr and mt.
+type SgIoBackend struct {
+	DeviceFile string
+	Timeout    time.Duration
+	file       *os.File
+}
+
+// NewSgIoBackend opens device for raw ioctl access. Call Close when done.
+func NewSgIoBackend(device string) (*SgIoBackend, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSgIoOpenFailed, err)
+	}
+	return &SgIoBackend{DeviceFile: device, Timeout: sgIoTimeoutMs * time.Millisecond, file: f}, nil
+}
+
+func (b *SgIoBackend) Close() error {
+	return b.file.Close()
+}
+
+// SupportsSgIo reports whether device's device file answers the SG_IO
+// ioctl at all, which is what BackendAuto uses to pick a backend.
+func SupportsSgIo(device string) bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	hdr := sgIoHdr{InterfaceID: sgInterfaceIDS, DxferDirection: sgDxferNone}
+	_, err = ioctlSgIo(f.Fd(), &hdr)
+	return err == nil
+}
+
+func ioctlSgIo(fd uintptr, hdr *sgIoHdr) (uintptr, error) {
+	if runtime.GOOS != "linux" {
+		return 0, ErrUnsupportedPlatform
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, uintptr(sgIoIoctlNumber), uintptr(unsafe.Pointer(hdr)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return 0, nil
+}
+
+// exec submits cdb through SG_IO, copying at most len(data) bytes of
+// returned data into data for DxferFromDev, or writing data out for
+// DxferToDev. dir must be sgDxferFromDev, sgDxferToDev or sgDxferNone.
+func (b *SgIoBackend) exec(cdb []byte, data []byte, dir int32) ([]byte, error) {
+	var sense [32]byte
+	hdr := sgIoHdr{
+		InterfaceID:    sgInterfaceIDS,
+		DxferDirection: dir,
+		CmdLen:         uint8(len(cdb)),
+		MxSbLen:        uint8(len(sense)),
+		DxferLen:       uint32(len(data)),
+		Timeout:        uint32(b.Timeout / time.Millisecond),
+	}
+	if len(cdb) > 0 {
+		hdr.Cmdp = uintptr(unsafe.Pointer(&cdb[0]))
+	}
+	if len(sense) > 0 {
+		hdr.Sbp = uintptr(unsafe.Pointer(&sense[0]))
+	}
+	if len(data) > 0 {
+		hdr.Dxferp = uintptr(unsafe.Pointer(&data[0]))
+	}
+	_, err := ioctlSgIo(b.file.Fd(), &hdr)
+	runtime.KeepAlive(cdb)
+	runtime.KeepAlive(sense)
+	runtime.KeepAlive(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSgIoExecFailed, err)
+	}
+	if hdr.MaskedStatus != 0 || hdr.HostStatus != 0 || hdr.DriverStatus != 0 {
+		return nil, fmt.Errorf("%w: status=%d host=%d driver=%d", ErrSgIoCheckCondition, hdr.MaskedStatus, hdr.HostStatus, hdr.DriverStatus)
+	}
+	return data, nil
+}
+
+// logSense issues LOG SENSE (4Dh) for pageCode with page control "current
+// cumulative values" and returns the raw log page (header plus
+// parameters), per SPC-4/SSC-5.
+func (b *SgIoBackend) logSense(pageCode byte) ([]byte, error) {
+	data := make([]byte, 0xFFFF)
+	return b.exec(logSenseCDB(pageCode), data, sgDxferFromDev)
+}
+
+// logSenseCDB builds the LOG SENSE (4Dh) CDB for pageCode with page
+// control "current cumulative values" (PC=01b) and subpage 0, per
+// SPC-4: byte 2 packs PC in bits 7-6 and PAGE CODE in bits 5-0, byte 3
+// is SUBPAGE CODE.
+func logSenseCDB(pageCode byte) []byte {
+	return []byte{0x4D, 0x00, 0x40 | pageCode, 0x00, 0, 0, 0, 0, 0xFF, 0xFF, 0}
+}
+
+// logSenseParams walks a log page's parameter list, calling fn with
+// each parameter's code and value bytes.
+func logSenseParams(resp []byte, fn func(code uint16, val []byte)) error {
+	if len(resp) < 4 {
+		return ErrSgIoBadResponse
+	}
+	pageLen := int(binary.BigEndian.Uint16(resp[2:4]))
+	body := resp[4:min(4+pageLen, len(resp))]
+	for len(body) >= 4 {
+		code := binary.BigEndian.Uint16(body[0:2])
+		paramLen := int(body[3])
+		if len(body) < 4+paramLen {
+			break
+		}
+		fn(code, body[4:4+paramLen])
+		body = body[4+paramLen:]
+	}
+	return nil
+}
+
+// bigEndianInt reinterprets val as a big endian unsigned integer.
+func bigEndianInt(val []byte) int64 {
+	var asInt64 int64
+	for _, byt := range val {
+		asInt64 = (asInt64 << 8) | int64(byt)
+	}
+	return asInt64
+}
+
+// GetCapacityLog issues LOG SENSE (4Dh) for the Tape Capacity log page
+// (31h) and parses the four capacity parameters (0001h-0004h), each a
+// four byte big endian value in MiB, per SSC-5.
+func (b *SgIoBackend) GetCapacityLog() (LtoTapeCapacityLog, error) {
+	capLog := LtoTapeCapacityLog{-1, -1, -1, -1}
+	resp, err := b.logSense(0x31)
+	if err != nil {
+		return capLog, err
+	}
+	err = logSenseParams(resp, func(code uint16, val []byte) {
+		switch code {
+		case 0x0001:
+			capLog.MainPartitionRemaining = bigEndianInt(val)
+		case 0x0002:
+			capLog.AlternatePartitionRemaining = bigEndianInt(val)
+		case 0x0003:
+			capLog.MainPartitionMax = bigEndianInt(val)
+		case 0x0004:
+			capLog.AlternatePartitionMax = bigEndianInt(val)
+		}
+	})
+	if err != nil {
+		return capLog, err
+	}
+	if capLog.AlternatePartitionMax == UnknownCapacity ||
+		capLog.AlternatePartitionRemaining == UnknownCapacity ||
+		capLog.MainPartitionMax == UnknownCapacity ||
+		capLog.MainPartitionRemaining == UnknownCapacity {
+		return capLog, ErrSomeCapacityLogFieldsMissing
+	}
+	return capLog, nil
+}
+
+// GetTapeAlertFlags issues LOG SENSE (4Dh) for the TapeAlert log page
+// (2Eh). Each standard condition is a one byte parameter whose value is
+// 1 when active, per SSC.
+func (b *SgIoBackend) GetTapeAlertFlags() ([]TapeAlertFlag, error) {
+	resp, err := b.logSense(0x2E)
+	if err != nil {
+		return nil, err
+	}
+	active := map[uint8]bool{}
+	err = logSenseParams(resp, func(code uint16, val []byte) {
+		active[uint8(code)] = len(val) > 0 && val[0] != 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildTapeAlertFlags(active), nil
+}
+
+// GetDriveHealth aggregates the Write Error Counter (02h), Read Error
+// Counter (03h) and Device Statistics (14h) log pages with TapeAlert.
+func (b *SgIoBackend) GetDriveHealth() (DriveStats, error) {
+	stats := DriveStats{}
+	if resp, err := b.logSense(0x02); err == nil {
+		logSenseParams(resp, func(code uint16, val []byte) {
+			if code == 0x0003 { // Total Errors Corrected, matches tapealert.go's sg_logs path.
+				stats.WriteErrors = bigEndianInt(val)
+			}
+		})
+	}
+	if resp, err := b.logSense(0x03); err == nil {
+		logSenseParams(resp, func(code uint16, val []byte) {
+			if code == 0x0003 { // Total Errors Corrected, matches tapealert.go's sg_logs path.
+				stats.ReadErrors = bigEndianInt(val)
+			}
+		})
+	}
+	if resp, err := b.logSense(0x14); err == nil {
+		logSenseParams(resp, func(code uint16, val []byte) {
+			switch code {
+			case 0x0002:
+				stats.LifetimeMBWritten = bigEndianInt(val)
+			case 0x0003:
+				stats.LifetimeMBRead = bigEndianInt(val)
+			case 0x0004:
+				stats.LoadCount = bigEndianInt(val)
+			case 0x0007:
+				stats.ThreadCount = bigEndianInt(val)
+			}
+		})
+	}
+	flags, err := b.GetTapeAlertFlags()
+	if err != nil {
+		return stats, err
+	}
+	stats.TapeAlerts = flags
+	return stats, nil
+}
+
+// ReadAttr issues READ ATTRIBUTE (8Ch) with service action 00h (ATTRIBUTE
+// VALUES) for a single attribute ID and returns its raw value bytes.
+func (b *SgIoBackend) ReadAttr(id uint16) ([]byte, error) {
+	cdb := make([]byte, 16)
+	cdb[0] = 0x8C
+	cdb[1] = 0x00 // Service action: attribute values.
+	binary.BigEndian.PutUint16(cdb[8:10], id)
+	binary.BigEndian.PutUint32(cdb[10:14], 0xFFFF)
+	data := make([]byte, 0xFFFF)
+	resp, err := b.exec(cdb, data, sgDxferFromDev)
+	if err != nil {
+		return nil, err
+	}
+	return parseReadAttrResponse(resp)
+}
+
+// parseReadAttrResponse extracts the VALUE bytes of a single attribute
+// from a READ ATTRIBUTE (8Ch) data-in buffer: 4 bytes AVAILABLE DATA,
+// then per attribute a 2-byte ID, 1-byte FORMAT and 2-byte LENGTH, then
+// LENGTH bytes of VALUE, per SPC-4.
+func parseReadAttrResponse(resp []byte) ([]byte, error) {
+	if len(resp) < 9 {
+		return nil, ErrSgIoBadResponse
+	}
+	attrLen := int(binary.BigEndian.Uint16(resp[7:9]))
+	if len(resp) < 9+attrLen {
+		return nil, ErrSgIoBadResponse
+	}
+	return resp[9 : 9+attrLen], nil
+}
+
+// HasDataCartridge reads attribute 0408h (medium type) via SG_IO.
+func (b *SgIoBackend) HasDataCartridge() (bool, error) {
+	val, err := b.ReadAttr(0x0408)
+	if err != nil {
+		return false, err
+	}
+	if len(val) == 0 {
+		return false, ErrSgIoBadResponse
+	}
+	return val[0] == ReadAttrMediumTypeDataCartridge, nil
+}
+
+// GetMediumSN reads attribute 0401h (medium serial number) via SG_IO.
+func (b *SgIoBackend) GetMediumSN() (string, error) {
+	val, err := b.ReadAttr(0x0401)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCanNotGetMediumSN, err)
+	}
+	return string(val), nil
+}
+
+// space issues SPACE (11h) with the given code and count, where count's
+// sign selects direction: the SSC-5 count field is a signed 24 bit
+// two's-complement value, negative meaning "space backward".
+func (b *SgIoBackend) space(code uint8, count int32) error {
+	cdb := make([]byte, 6)
+	cdb[0] = 0x11 // SPACE
+	cdb[1] = code
+	cdb[2] = byte(count >> 16)
+	cdb[3] = byte(count >> 8)
+	cdb[4] = byte(count)
+	_, err := b.exec(cdb, nil, sgDxferNone)
+	return err
+}
+
+// FSF issues SPACE (11h) with code 01h (filemarks) to skip forward count
+// filemarks.
+func (b *SgIoBackend) FSF(count uint32) error {
+	return b.space(0x01, int32(count))
+}
+
+// BSF issues SPACE (11h) with code 01h and a negative count to back up
+// over count filemarks.
+func (b *SgIoBackend) BSF(count uint32) error {
+	return b.space(0x01, -int32(count))
+}
+
+// WEOF issues WRITE FILEMARKS (10h) to write one filemark.
+func (b *SgIoBackend) WEOF() error {
+	cdb := []byte{0x10, 0x00, 0, 0, 0x01, 0}
+	_, err := b.exec(cdb, nil, sgDxferNone)
+	return err
+}
+
+// Rewind issues REWIND (01h).
+func (b *SgIoBackend) Rewind() error {
+	cdb := []byte{0x01, 0x00, 0, 0, 0, 0}
+	_, err := b.exec(cdb, nil, sgDxferNone)
+	return err
+}
+
+// Erase issues ERASE (19h) with the LONG bit clear (short erase).
+func (b *SgIoBackend) Erase() error {
+	cdb := []byte{0x19, 0x00, 0, 0, 0, 0}
+	_, err := b.exec(cdb, nil, sgDxferNone)
+	return err
+}
+
+// Eject issues LOAD/UNLOAD (1Bh) with the LOAD bit clear.
+func (b *SgIoBackend) Eject() error {
+	cdb := []byte{0x1B, 0x00, 0, 0, 0, 0}
+	_, err := b.exec(cdb, nil, sgDxferNone)
+	return err
+}
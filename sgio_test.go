@@ -0,0 +1,72 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 17:00:00
+ * @LastEditTime: 2026-07-27 17:00:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/sgio_test.go
+ */
+
+package ltouwrap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLogSenseCDB(t *testing.T) {
+	cases := []struct {
+		pageCode byte
+		want     []byte
+	}{
+		{0x31, []byte{0x4D, 0x00, 0x71, 0x00, 0, 0, 0, 0, 0xFF, 0xFF, 0}},
+		{0x2E, []byte{0x4D, 0x00, 0x6E, 0x00, 0, 0, 0, 0, 0xFF, 0xFF, 0}},
+		{0x02, []byte{0x4D, 0x00, 0x42, 0x00, 0, 0, 0, 0, 0xFF, 0xFF, 0}},
+	}
+	for _, c := range cases {
+		got := logSenseCDB(c.pageCode)
+		if !bytes.Equal(got, c.want) {
+			t.Errorf("logSenseCDB(0x%02X) = % X, want % X", c.pageCode, got, c.want)
+		}
+	}
+}
+
+func TestParseReadAttrResponse(t *testing.T) {
+	// 4 bytes AVAILABLE DATA, then ID=0401h, FORMAT=00h, LENGTH=0004h,
+	// VALUE="ABCD".
+	resp := []byte{0, 0, 0, 9, 0x04, 0x01, 0x00, 0x00, 0x04, 'A', 'B', 'C', 'D'}
+	val, err := parseReadAttrResponse(resp)
+	if err != nil {
+		t.Fatalf("parseReadAttrResponse: %v", err)
+	}
+	if string(val) != "ABCD" {
+		t.Fatalf("parseReadAttrResponse = %q, want %q", val, "ABCD")
+	}
+}
+
+func TestParseReadAttrResponseTruncated(t *testing.T) {
+	if _, err := parseReadAttrResponse([]byte{0, 0, 0, 9, 0x04, 0x01, 0x00, 0x00, 0xFF}); !errors.Is(err, ErrSgIoBadResponse) {
+		t.Fatalf("parseReadAttrResponse err = %v, want ErrSgIoBadResponse", err)
+	}
+	if _, err := parseReadAttrResponse([]byte{0, 0, 0}); !errors.Is(err, ErrSgIoBadResponse) {
+		t.Fatalf("parseReadAttrResponse err = %v, want ErrSgIoBadResponse", err)
+	}
+}
+
+func TestLogSenseParams(t *testing.T) {
+	// Page header (4 bytes) + one 4-byte-value parameter at code 0x0003.
+	resp := []byte{0x31, 0x00, 0x00, 0x08, 0x00, 0x03, 0x00, 0x04, 0, 0, 0x04, 0x00}
+	var got int64 = -1
+	err := logSenseParams(resp, func(code uint16, val []byte) {
+		if code == 0x0003 {
+			got = bigEndianInt(val)
+		}
+	})
+	if err != nil {
+		t.Fatalf("logSenseParams: %v", err)
+	}
+	if got != 0x0400 {
+		t.Fatalf("logSenseParams parsed value = %d, want %d", got, 0x0400)
+	}
+}
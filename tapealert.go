@@ -0,0 +1,210 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 15:30:00
+ * @LastEditTime: 2026-07-27 15:30:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/tapealert.go
+ */
+
+package ltouwrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrCanNotGetTapeAlertFlags error = errors.New("can not get tape alert flags")
+var ErrCanNotGetDriveHealth error = errors.New("can not get drive health")
+
+// tapeAlertEntry is the static name/severity of a standard TapeAlert ID.
+type tapeAlertEntry struct {
+	Name     string
+	Severity string
+}
+
+// The 64 standard TapeAlert conditions, per the SSC TapeAlert log page
+// (2Eh). IDs with no assigned meaning are omitted.
+var tapeAlertCatalog = map[uint8]tapeAlertEntry{
+	1:  {"Read warning", "Warning"},
+	2:  {"Write warning", "Warning"},
+	3:  {"Hard error", "Critical"},
+	4:  {"Media", "Critical"},
+	5:  {"Read failure", "Critical"},
+	6:  {"Write failure", "Critical"},
+	7:  {"Media life", "Warning"},
+	8:  {"Not data grade", "Warning"},
+	9:  {"Write protect", "Information"},
+	10: {"No removal", "Information"},
+	11: {"Cleaning media", "Information"},
+	12: {"Unsupported format", "Critical"},
+	13: {"Recoverable mechanical cartridge failure", "Warning"},
+	14: {"Unrecoverable mechanical cartridge failure", "Critical"},
+	15: {"Memory chip in cartridge failure", "Warning"},
+	16: {"Forced eject", "Warning"},
+	17: {"Read only format", "Information"},
+	18: {"Tape directory corrupted on load", "Warning"},
+	19: {"Nearing media life", "Warning"},
+	20: {"Clean now", "Critical"},
+	21: {"Clean periodic", "Warning"},
+	22: {"Expired cleaning media", "Warning"},
+	23: {"Invalid cleaning tape", "Warning"},
+	24: {"Retension requested", "Information"},
+	25: {"Dual port interface error", "Warning"},
+	26: {"Cooling fan failure", "Warning"},
+	27: {"Power supply failure", "Warning"},
+	28: {"Power consumption", "Warning"},
+	29: {"Drive maintenance", "Warning"},
+	30: {"Hardware A", "Critical"},
+	31: {"Hardware B", "Critical"},
+	32: {"Interface", "Critical"},
+	33: {"Eject media", "Information"},
+	34: {"Microcode update fail", "Warning"},
+	35: {"Drive humidity", "Warning"},
+	36: {"Drive temperature", "Warning"},
+	37: {"Drive voltage", "Warning"},
+	38: {"Predictive failure", "Warning"},
+	39: {"Diagnostics required", "Warning"},
+	42: {"Loader hardware A", "Critical"},
+	43: {"Loader stray tape", "Warning"},
+	44: {"Loader hardware B", "Critical"},
+	45: {"Loader door", "Warning"},
+	46: {"Loader hardware C", "Critical"},
+	47: {"Loader magazine", "Warning"},
+	49: {"Tape directory invalid at unload", "Warning"},
+	50: {"Tape system area write failure", "Critical"},
+	51: {"Tape system area read failure", "Critical"},
+	52: {"No start of data", "Critical"},
+	53: {"Loading failure", "Critical"},
+	54: {"Unrecoverable unload failure", "Critical"},
+	55: {"Automation interface failure", "Warning"},
+	56: {"Firmware failure", "Critical"},
+	57: {"WORM medium - integrity check failed", "Critical"},
+	58: {"WORM medium - overwrite attempted", "Warning"},
+}
+
+var sgLogsFlagLineRe = regexp.MustCompile(`(?i)^Flag([0-9a-f]{2})h.*:\s*([01])\s*$`)
+
+// GetTapeAlertFlags reads LOG SENSE page 2Eh (TapeAlert) and reports the
+// state of every standard TapeAlert condition known to this package.
+func (device *LtoNoRewindTapeDrive) GetTapeAlertFlags() ([]TapeAlertFlag, error) {
+	if device.useSgIo() {
+		b, err := device.sgIoBackend()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCanNotGetTapeAlertFlags, err)
+		}
+		defer b.Close()
+		return b.GetTapeAlertFlags()
+	}
+	cmdOut, err := device.executor().RunSgLogs(context.Background(), "-p", "0x2e", device.DeviceFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCanNotGetTapeAlertFlags, fmt.Errorf("%w: %w", ErrSgLogsExecFailed, err))
+	}
+	active := map[uint8]bool{}
+	for _, line := range strings.Split(string(cmdOut), "\n") {
+		m := sgLogsFlagLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		id, err := parseHexByte(m[1])
+		if err != nil {
+			continue
+		}
+		active[id] = m[2] == "1"
+	}
+	return buildTapeAlertFlags(active), nil
+}
+
+func buildTapeAlertFlags(active map[uint8]bool) []TapeAlertFlag {
+	flags := make([]TapeAlertFlag, 0, len(tapeAlertCatalog))
+	for id := uint8(1); id <= 64; id++ {
+		entry, ok := tapeAlertCatalog[id]
+		if !ok {
+			continue
+		}
+		flags = append(flags, TapeAlertFlag{ID: id, Name: entry.Name, Severity: entry.Severity, Active: active[id]})
+	}
+	return flags
+}
+
+func parseHexByte(s string) (uint8, error) {
+	val, err := strconv.ParseUint(s, 16, 8)
+	if err != nil {
+		return 0, err
+	}
+	return uint8(val), nil
+}
+
+// GetDriveHealth aggregates the Read/Write Error Counter log pages
+// (02h/03h), the Device Statistics log page (14h), and TapeAlert, so
+// operators have one call to check for failing media or a drive that
+// needs cleaning.
+func (device *LtoNoRewindTapeDrive) GetDriveHealth() (DriveStats, error) {
+	if device.useSgIo() {
+		b, err := device.sgIoBackend()
+		if err != nil {
+			return DriveStats{}, fmt.Errorf("%w: %w", ErrCanNotGetDriveHealth, err)
+		}
+		defer b.Close()
+		return b.GetDriveHealth()
+	}
+	var errs error
+	stats := DriveStats{}
+	if writeOut, err := device.executor().RunSgLogs(context.Background(), "-p", "0x02", device.DeviceFile); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("%w: %w", ErrSgLogsExecFailed, err))
+	} else if val, err := findSgLogsIntField(string(writeOut), "Total errors corrected"); err == nil {
+		stats.WriteErrors = val
+	} else {
+		errs = errors.Join(errs, err)
+	}
+	if readOut, err := device.executor().RunSgLogs(context.Background(), "-p", "0x03", device.DeviceFile); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("%w: %w", ErrSgLogsExecFailed, err))
+	} else if val, err := findSgLogsIntField(string(readOut), "Total errors corrected"); err == nil {
+		stats.ReadErrors = val
+	} else {
+		errs = errors.Join(errs, err)
+	}
+	if statOut, err := device.executor().RunSgLogs(context.Background(), "-p", "0x14", device.DeviceFile); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("%w: %w", ErrSgLogsExecFailed, err))
+	} else {
+		out := string(statOut)
+		if val, err := findSgLogsIntField(out, "Lifetime megabytes written"); err == nil {
+			stats.LifetimeMBWritten = val
+		}
+		if val, err := findSgLogsIntField(out, "Lifetime megabytes read"); err == nil {
+			stats.LifetimeMBRead = val
+		}
+		if val, err := findSgLogsIntField(out, "Lifetime load count"); err == nil {
+			stats.LoadCount = val
+		}
+		if val, err := findSgLogsIntField(out, "Lifetime thread count"); err == nil {
+			stats.ThreadCount = val
+		}
+	}
+	flags, err := device.GetTapeAlertFlags()
+	if err != nil {
+		errs = errors.Join(errs, err)
+	}
+	stats.TapeAlerts = flags
+	if errs != nil {
+		return stats, fmt.Errorf("%w: %w", ErrCanNotGetDriveHealth, errs)
+	}
+	return stats, nil
+}
+
+// findSgLogsIntField looks for a sg_logs output line starting with
+// prefix and parses its trailing integer, the same way GetCapacityLog
+// parses the Tape Capacity log page.
+func findSgLogsIntField(output string, prefix string) (int64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		tmp := strings.TrimSpace(line)
+		if strings.HasPrefix(tmp, prefix) {
+			return extractSgIntVal(tmp, 0)
+		}
+	}
+	return 0, ErrSomeSgRelatedFieldsMissing
+}
@@ -0,0 +1,103 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 17:15:00
+ * @LastEditTime: 2026-07-27 17:15:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/tapealert_test.go
+ */
+
+package ltouwrap
+
+import (
+	"testing"
+
+	"github.com/FunctionSir/ltouwrap/faketest"
+)
+
+func TestBuildTapeAlertFlags(t *testing.T) {
+	flags := buildTapeAlertFlags(map[uint8]bool{3: true, 7: true})
+	if len(flags) != len(tapeAlertCatalog) {
+		t.Fatalf("buildTapeAlertFlags returned %d flags, want %d", len(flags), len(tapeAlertCatalog))
+	}
+	for _, f := range flags {
+		want := f.ID == 3 || f.ID == 7
+		if f.Active != want {
+			t.Errorf("flag %d Active = %v, want %v", f.ID, f.Active, want)
+		}
+	}
+}
+
+func TestGetTapeAlertFlagsViaSgLogs(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgLogs(
+		"TapeAlert log page:\n"+
+			"  Flag03h (Hard error): 1\n"+
+			"  Flag07h (Media life): 0\n",
+		"-p", "0x2e", "/dev/nst0")
+	device := newTestDevice(fe)
+	flags, err := device.GetTapeAlertFlags()
+	if err != nil {
+		t.Fatalf("GetTapeAlertFlags: %v", err)
+	}
+	for _, f := range flags {
+		switch f.ID {
+		case 3:
+			if !f.Active {
+				t.Errorf("flag 3 (%s) = inactive, want active", f.Name)
+			}
+		case 7:
+			if f.Active {
+				t.Errorf("flag 7 (%s) = active, want inactive", f.Name)
+			}
+		}
+	}
+}
+
+func TestFindSgLogsIntField(t *testing.T) {
+	out := "Total errors corrected: 42\nSome other field: 7\n"
+	val, err := findSgLogsIntField(out, "Total errors corrected")
+	if err != nil {
+		t.Fatalf("findSgLogsIntField: %v", err)
+	}
+	if val != 42 {
+		t.Fatalf("findSgLogsIntField = %d, want 42", val)
+	}
+	if _, err := findSgLogsIntField(out, "Not present"); err == nil {
+		t.Fatalf("findSgLogsIntField: want error for missing field")
+	}
+}
+
+func TestGetDriveHealthViaSgLogs(t *testing.T) {
+	fe := faketest.NewFakeExecutor()
+	fe.OnSgLogs("Total errors corrected: 1\n", "-p", "0x02", "/dev/nst0")
+	fe.OnSgLogs("Total errors corrected: 2\n", "-p", "0x03", "/dev/nst0")
+	fe.OnSgLogs(
+		"Lifetime megabytes written: 1000\n"+
+			"Lifetime megabytes read: 2000\n"+
+			"Lifetime load count: 3\n"+
+			"Lifetime thread count: 4\n",
+		"-p", "0x14", "/dev/nst0")
+	fe.OnSgLogs("TapeAlert log page:\n", "-p", "0x2e", "/dev/nst0")
+	device := newTestDevice(fe)
+	stats, err := device.GetDriveHealth()
+	if err != nil {
+		t.Fatalf("GetDriveHealth: %v", err)
+	}
+	want := DriveStats{
+		WriteErrors:       1,
+		ReadErrors:        2,
+		LifetimeMBWritten: 1000,
+		LifetimeMBRead:    2000,
+		LoadCount:         3,
+		ThreadCount:       4,
+	}
+	if stats.WriteErrors != want.WriteErrors || stats.ReadErrors != want.ReadErrors ||
+		stats.LifetimeMBWritten != want.LifetimeMBWritten || stats.LifetimeMBRead != want.LifetimeMBRead ||
+		stats.LoadCount != want.LoadCount || stats.ThreadCount != want.ThreadCount {
+		t.Fatalf("GetDriveHealth = %+v, want %+v", stats, want)
+	}
+	if len(stats.TapeAlerts) != len(tapeAlertCatalog) {
+		t.Fatalf("GetDriveHealth TapeAlerts len = %d, want %d", len(stats.TapeAlerts), len(tapeAlertCatalog))
+	}
+}
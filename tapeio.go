@@ -0,0 +1,381 @@
+/*
+ * @Author: FunctionSir
+ * @Date: 2026-07-27 16:00:00
+ * @LastEditTime: 2026-07-27 16:00:00
+ * @LastEditors: FunctionSir
+ * @Description: -
+ * @FilePath: /ltouwrap/tapeio.go
+ */
+
+package ltouwrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultTapeBlockSize is the fixed block size TapeWriter/TapeReader use
+// when TapeIOOptions.BlockSize is zero, matching LTO's native block size.
+const DefaultTapeBlockSize = 256 * 1024
+
+// DefaultRingBufferBlocks is how many blocks TapeWriter/TapeReader
+// buffer internally when TapeIOOptions.RingBufferBlocks is zero.
+const DefaultRingBufferBlocks = 4
+
+var ErrCanNotOpenTapeForIO error = errors.New("can not open device file for streaming io")
+var ErrTapeWriterClosed error = errors.New("tape writer is closed")
+var ErrTapeReaderClosed error = errors.New("tape reader is closed")
+
+// RateLimit bounds how fast a TapeWriter/TapeReader moves bytes.
+// Modelled on the blkio throttle knobs container runtimes expose: a
+// floor so a slow producer/consumer doesn't shoe-shine the drive, and a
+// ceiling so streaming doesn't starve other tape or host I/O.
+type RateLimit struct {
+	// FloorBytesPerSec is the rate TapeWriter/TapeReader expects to be
+	// kept fed at. It only affects how MinimumFlushInterval defaults;
+	// it is not separately enforced.
+	FloorBytesPerSec int64
+	// CeilingBytesPerSec caps the rate bytes are written/read at. Zero
+	// means unlimited.
+	CeilingBytesPerSec int64
+}
+
+// TapeIOOptions configures TapeWriter and TapeReader.
+type TapeIOOptions struct {
+	// BlockSize is the fixed block size used for every read/write
+	// syscall against the device file. Zero means DefaultTapeBlockSize.
+	BlockSize int
+	// RingBufferBlocks sizes the internal ring buffer, in multiples of
+	// BlockSize, so a producer/consumer that can't keep a steady pace
+	// doesn't force the drive to stop and reposition (shoe-shining).
+	// Zero means DefaultRingBufferBlocks.
+	RingBufferBlocks int
+	RateLimit        RateLimit
+	// MinimumFlushInterval is how long TapeWriter waits for a full
+	// block before treating the producer as starved. Zero derives it
+	// from RateLimit.FloorBytesPerSec, falling back to one second.
+	MinimumFlushInterval time.Duration
+	// PadWithFilemarks lets TapeWriter write a filemark instead of
+	// parking when starved past MinimumFlushInterval. Most callers
+	// (e.g. the archive package) want this left false, since a
+	// filemark mid-stream ends the current tape file.
+	PadWithFilemarks bool
+}
+
+func (o TapeIOOptions) blockSize() int {
+	if o.BlockSize > 0 {
+		return o.BlockSize
+	}
+	return DefaultTapeBlockSize
+}
+
+func (o TapeIOOptions) ringBufferBlocks() int {
+	if o.RingBufferBlocks > 0 {
+		return o.RingBufferBlocks
+	}
+	return DefaultRingBufferBlocks
+}
+
+func (o TapeIOOptions) minimumFlushInterval() time.Duration {
+	if o.MinimumFlushInterval > 0 {
+		return o.MinimumFlushInterval
+	}
+	if o.RateLimit.FloorBytesPerSec > 0 {
+		seconds := float64(o.blockSize()) / float64(o.RateLimit.FloorBytesPerSec)
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
+
+// TapeWriter opens the device file for direct block writes, matching
+// BlockSize, with a ring buffer absorbing bursts from a producer that
+// can't feed the drive at a steady pace.
+func (device *LtoNoRewindTapeDrive) TapeWriter(ctx context.Context, opts TapeIOOptions) (io.WriteCloser, error) {
+	f, err := os.OpenFile(device.DeviceFile, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCanNotOpenTapeForIO, err)
+	}
+	return newTapeWriter(ctx, f, opts, func() error { return device.WEOFCtx(ctx) }), nil
+}
+
+// TapeReader opens the device file for direct block reads, matching
+// BlockSize, with a ring buffer absorbing bursts a consumer can't drain
+// at a steady pace.
+func (device *LtoNoRewindTapeDrive) TapeReader(ctx context.Context, opts TapeIOOptions) (io.ReadCloser, error) {
+	f, err := os.OpenFile(device.DeviceFile, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCanNotOpenTapeForIO, err)
+	}
+	return newTapeReader(ctx, f, opts), nil
+}
+
+// TapeWriter streams fixed block-size writes to a tape device file
+// through a ring buffer, optionally rate limited.
+type TapeWriter struct {
+	file      *os.File
+	blockSize int
+	opts      TapeIOOptions
+	padFunc   func() error
+	blocks    chan []byte
+	pending   []byte
+	done      chan struct{}
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+func newTapeWriter(ctx context.Context, f *os.File, opts TapeIOOptions, padFunc func() error) *TapeWriter {
+	w := &TapeWriter{
+		file:      f,
+		blockSize: opts.blockSize(),
+		opts:      opts,
+		padFunc:   padFunc,
+		blocks:    make(chan []byte, opts.ringBufferBlocks()),
+		done:      make(chan struct{}),
+		pending:   make([]byte, 0, opts.blockSize()),
+	}
+	go w.run(ctx)
+	return w
+}
+
+// send hands block to run, but gives up once run has already exited (on a
+// prior write error or ctx cancellation) instead of blocking forever on a
+// full channel nobody is draining.
+func (w *TapeWriter) send(block []byte) {
+	select {
+	case w.blocks <- block:
+	case <-w.done:
+	}
+}
+
+func (w *TapeWriter) Write(p []byte) (int, error) {
+	if w.loadErr() != nil {
+		return 0, w.loadErr()
+	}
+	total := 0
+	for len(p) > 0 {
+		free := w.blockSize - len(w.pending)
+		n := min(free, len(p))
+		w.pending = append(w.pending, p[:n]...)
+		p = p[n:]
+		total += n
+		if len(w.pending) == w.blockSize {
+			w.send(w.pending)
+			w.pending = make([]byte, 0, w.blockSize)
+		}
+	}
+	return total, w.loadErr()
+}
+
+func (w *TapeWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrTapeWriterClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.send(w.pending)
+		w.pending = nil
+	}
+	close(w.blocks)
+	<-w.done
+	closeErr := w.file.Close()
+	if err := w.loadErr(); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// run drains w.blocks onto the device file. ctx is only checked between
+// blocks: once file.Write is in flight there is no way to interrupt the
+// underlying syscall, so a stalled drive can make Close block until it
+// clears on its own.
+func (w *TapeWriter) run(ctx context.Context) {
+	defer close(w.done)
+	minFlush := w.opts.minimumFlushInterval()
+	timer := time.NewTimer(minFlush)
+	defer timer.Stop()
+	var written, start = int64(0), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			w.storeErr(ctx.Err())
+			return
+		case block, ok := <-w.blocks:
+			if !ok {
+				return
+			}
+			drainTimer(timer)
+			timer.Reset(minFlush)
+			throttle(&written, start, len(block), w.opts.RateLimit.CeilingBytesPerSec)
+			if _, err := w.file.Write(block); err != nil {
+				w.storeErr(err)
+				return
+			}
+			written += int64(len(block))
+		case <-timer.C:
+			timer.Reset(minFlush)
+			if w.opts.PadWithFilemarks {
+				if err := w.padFunc(); err != nil {
+					w.storeErr(err)
+					return
+				}
+				continue
+			}
+			// Otherwise park: keep waiting for the next block rather
+			// than force the drive to write a short, wasteful one.
+		}
+	}
+}
+
+func (w *TapeWriter) storeErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *TapeWriter) loadErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// TapeReader streams fixed block-size reads from a tape device file
+// through a ring buffer, optionally rate limited.
+type TapeReader struct {
+	file      *os.File
+	blockSize int
+	opts      TapeIOOptions
+	blocks    chan []byte
+	pending   []byte
+	done      chan struct{}
+	cancel    context.CancelFunc
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+func newTapeReader(ctx context.Context, f *os.File, opts TapeIOOptions) *TapeReader {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &TapeReader{
+		file:      f,
+		blockSize: opts.blockSize(),
+		opts:      opts,
+		blocks:    make(chan []byte, opts.ringBufferBlocks()),
+		done:      make(chan struct{}),
+		cancel:    cancel,
+	}
+	go r.run(ctx)
+	return r
+}
+
+// run fills r.blocks from the device file. Like TapeWriter.run, ctx is
+// only checked between reads: an in-flight file.Read can't be
+// interrupted by ctx alone.
+func (r *TapeReader) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.blocks)
+	buf := make([]byte, r.blockSize)
+	var read, start = int64(0), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		throttle(&read, start, r.blockSize, r.opts.RateLimit.CeilingBytesPerSec)
+		n, err := r.file.Read(buf)
+		if n > 0 {
+			block := make([]byte, n)
+			copy(block, buf[:n])
+			select {
+			case r.blocks <- block:
+			case <-ctx.Done():
+				return
+			}
+			read += int64(n)
+		}
+		if err != nil {
+			if err != io.EOF {
+				r.storeErr(err)
+			}
+			return
+		}
+	}
+}
+
+func (r *TapeReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		block, ok := <-r.blocks
+		if !ok {
+			if err := r.loadErr(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.pending = block
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *TapeReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrTapeReaderClosed
+	}
+	r.closed = true
+	r.mu.Unlock()
+	r.cancel()
+	<-r.done
+	return r.file.Close()
+}
+
+func (r *TapeReader) storeErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *TapeReader) loadErr() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func drainTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// throttle sleeps as needed so that, once nextChunk bytes are added to
+// *moved, the average rate since start stays at or below ceiling. A
+// non-positive ceiling disables throttling.
+func throttle(moved *int64, start time.Time, nextChunk int, ceiling int64) {
+	if ceiling <= 0 {
+		return
+	}
+	wantElapsed := time.Duration(float64(*moved+int64(nextChunk)) / float64(ceiling) * float64(time.Second))
+	if actual := time.Since(start); wantElapsed > actual {
+		time.Sleep(wantElapsed - actual)
+	}
+}